@@ -2,27 +2,42 @@ package main
 
 import (
 	"bufio"
+	_ "embed"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"unicode"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 const USAGE = `USAGE: genkeys <COMP/WM> [CONFIG]
+       genkeys watch <COMP/WM> [CONFIG]
 genkeys is a program that reads a file containing keybinding definitions and outputs a config file compatible with many wayland compositors/window managers.
 
 	COMP/WM   Make genkeys dump the keybinding definitions in the configuration format used by <COMP/WM>.
-		Supported compositors/window managers are:
+		Built-in compositors/window managers are:
 			sway/i3
 			hyprland
 			all
+		Any other name is looked up as a custom backend, either configured via 'Templates' in the
+		genkeys config or found at '$HOME/.config/genkeys/templates/<COMP/WM>.tmpl'. For more
+		details, see 'help templates'.
 		If 'help' is provided instead, it will print this help.
 	CONFIG    The file containing the keybinding definitons. Defaults to '$HOME/.config/genkeys.gnks'. For more details, see 'help key_defs'.
+	watch     Instead of generating the config once, keep running and regenerate it whenever CONFIG
+		(or anything it includes) changes, optionally running 'ReloadCommand' from the genkeys
+		config afterwards. See 'help configuring'.
 
 	This program is also capable of saving the generated configs in a specified file. For more details, see 'help configuring'.`
 
@@ -31,17 +46,100 @@ genkeys will try to find its configuration file at '$HOME/.config/genkeys.json'.
 	{
 		"WriteToFile": true,
 		"HyprlandPath": "/home/user/.config/hypr/keys.conf",
-		"SwayPath": "/home/user/.config/sway/keys.conf"
+		"SwayPath": "/home/user/.config/sway/keys.conf",
+		"Templates": {
+			"river": {
+				"Path": "/home/user/.config/genkeys/templates/river.tmpl",
+				"Output": "/home/user/.config/river/init"
+			}
+		},
+		"ReloadCommand": "swaymsg reload"
 	}
 WriteToFile: Is a boolean indicating whether genkeys should write its output to a file or not.
-HyprlandPath/SwayPath: These are the paths of the files where genkeys should write its output to.`
+HyprlandPath/SwayPath: These are the paths of the files where genkeys should write its output to.
+Templates: Maps a backend name (the <COMP/WM> argument) to a custom template file and the output
+	path it should be rendered to. This can also be used to override the built-in 'sway'/'hyprland'
+	templates by pointing 'Path' at your own '.tmpl' file.
+ReloadCommand: Run through 'sh -c' after 'genkeys watch' regenerates a config, e.g. 'swaymsg reload'
+	or 'hyprctl reload', so the compositor picks up the new bindings on its own.`
+
+const TEMPLATES_USAGE = `Custom backends:
+genkeys can render keybindings through any Go 'text/template' file, not just the built-in Sway and
+Hyprland backends. To add support for a new compositor/window manager, drop a template at
+'$HOME/.config/genkeys/templates/<name>.tmpl' (or register it under 'Templates' in the genkeys
+config) and invoke 'genkeys <name>'.
+
+The template is executed once per keybinding, with the following data:
+	.Keys     A list of the keys in the binding, each with:
+			.Kind   "Super", "Shift", "Ctrl", "Alt", "Meta", "Print", "Enter", "Function",
+				"KP", "XF86", "Mouse", "Scroll" or "Char"
+			.Char   The character, for "Char" keys
+			.Num    The function-key/numpad/mouse-button number, for "Function", "KP" and
+				"Mouse" keys
+			.Name   A generic, backend-agnostic name for the key (e.g. "F5", "KP_5",
+				"XF86AudioMute", "Mouse3", "ScrollUp")
+	.Command  The shell command to run
+	.Release  True if the binding should fire on key release rather than key press
+
+Helper functions available inside templates:
+	join        strings.Join
+	upper       strings.ToUpper
+	quote       Quotes a string the way Go source would
+	modName     Renders a key's name for a given dialect ("sway", "hyprland", ...), falling back
+			to .Name if the dialect doesn't know about that key
+	numpadName  Renders a "KP" key's name; same as .Name, kept for templates that want to
+			single out numpad keys explicitly
+
+Example ('river.tmpl'):
+	riverctl map normal {{range $i, $k := .Keys}}{{if $i}}+{{end}}{{$k.Name}}{{end}} spawn {{quote .Command}}`
 
 const KEYBINDINGS_USAGE = `Defining keybindings:
 Keybindings are defined the following way:
 	bind "<keys>" "<shell command>"
 Where <keys> are the keys that should be pressed in order to run <shell command>. Separated by spaces.
 Example:
-	bind "Super Shift Print" "slurp | grim -g - $(xdg-user-dir PICTURES)/screenshot.png"`
+	bind "Super Shift Print" "slurp | grim -g - $(xdg-user-dir PICTURES)/screenshot.png"
+
+<keys> can contain:
+	Modifiers    Super, Shift, Ctrl, Alt, Meta
+	Function     F1-F24
+	Media keys   any XF86 keysym, e.g. XF86AudioRaiseVolume, XF86MonBrightnessDown
+	Numpad       KP_0-KP_9, KP_Enter, KP_Add
+	Mouse        Mouse1-Mouse9
+	Scroll       ScrollUp, ScrollDown
+	Misc         Print, Enter, or any single character
+A combination can also contain the pseudo-key 'release', which fires the binding on key release
+instead of key press (emitted as 'bindsym --release' on Sway, a 'bindr' on Hyprland):
+	bind "Super Q release" "notify-send released"
+
+Splitting keybindings across files:
+A keydefs file can pull in other keydefs files with 'include'/'include_dir', modeled on OpenSSH's
+'Include':
+	include "~/.config/genkeys/media.gnks"
+	include_dir "~/.config/genkeys/d/"
+'include' takes the path of a single file; 'include_dir' takes a directory and pulls in every
+'*.gnks' file directly inside it. Relative paths are resolved relative to the file doing the
+including, and '~'/'$HOME' are expanded. Including a file that's already being parsed (directly or
+through a chain of other includes) is an error.
+
+Conditional bindings:
+A 'bind' can be scoped to only apply on some machines or to some backends with a 'when' block,
+modeled on ssh_config's 'Match'/'Host':
+	when host "laptop.*" {
+		bind "Super F1" "brightnessctl set 10%-"
+	}
+	when target "hyprland" {
+		bind "Super Shift L" "hyprlock"
+	}
+Supported predicates are 'host <hostname regex>', 'target <backend name>', 'env <name> <value>'
+and 'file_exists <path>'. 'when' blocks can be nested, in which case a binding only applies if
+every enclosing predicate holds.`
+
+//go:embed templates/sway.tmpl
+var swayTemplateSrc string
+
+//go:embed templates/hyprland.tmpl
+var hyprlandTemplateSrc string
 
 func isDigitsOnly(s string) bool {
 	if len(s) == 0 { return false }
@@ -53,11 +151,49 @@ func isDigitsOnly(s string) bool {
 	return true
 }
 
+// dieError is how die() reports a failure while softDie is active, instead
+// of exiting the process outright. See withDieAsError.
+type dieError string
+
+func (e dieError) Error() string { return string(e) }
+
+// softDie, while true, makes die() panic with a dieError instead of exiting
+// the process. It's only ever set by withDieAsError, which recovers the
+// panic at its boundary and turns it back into a plain `error` — this is
+// what lets watch mode survive a typo in the keydefs file instead of
+// taking the whole daemon down with it.
+var softDie bool
+
 func die(msg string, a ...any) {
-	fmt.Fprintf(os.Stderr, fmt.Sprintf("%s\n", msg), a...)
+	formatted := fmt.Sprintf(msg, a...)
+	if softDie {
+		panic(dieError(formatted))
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", formatted)
 	os.Exit(1)
 }
 
+// withDieAsError runs fn with die() turned into a recoverable error rather
+// than a fatal os.Exit, returning that error instead of letting it unwind
+// any further.
+func withDieAsError(fn func()) (err error) {
+	prev := softDie
+	softDie = true
+	defer func() {
+		softDie = prev
+		if r := recover(); r != nil {
+			de, ok := r.(dieError)
+			if !ok {
+				panic(r)
+			}
+			err = de
+		}
+	}()
+
+	fn()
+	return nil
+}
+
 func readFileToString(fpath string) (filecontent string) {
 	b, err := ioutil.ReadFile(fpath)
 	if err != nil {
@@ -80,10 +216,19 @@ func getStream(file string) *os.File {
 	return stream
 }
 
+func expandUserPath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		return path.Join(os.Getenv("HOME"), strings.TrimPrefix(p, "~"))
+	}
+	return p
+}
+
 type TokenKind int
 const (
-	TOKEN_WORD TokenKind = iota
-	TOKEN_STR  TokenKind = iota
+	TOKEN_WORD   TokenKind = iota
+	TOKEN_STR    TokenKind = iota
+	TOKEN_LBRACE TokenKind = iota
+	TOKEN_RBRACE TokenKind = iota
 )
 
 type TokenLocation struct {
@@ -154,6 +299,21 @@ func lex(fname string, str string) []Token {
 			tokens = append(tokens,
 				Token{Kind: TOKEN_STR, Text: tokenText, Loc: strLoc})
 			tokenText = ""
+		case char == '{' || char == '}':
+			if tokenText != "" {
+				token_loc := location
+				token_loc.Col -= len(strings.TrimSpace(tokenText))
+				tokens = append(tokens,
+					Token{Kind: TOKEN_WORD, Text: strings.TrimSpace(tokenText), Loc: token_loc})
+				tokenText = ""
+			}
+
+			braceKind := TOKEN_LBRACE
+			if char == '}' {
+				braceKind = TOKEN_RBRACE
+			}
+			tokens = append(tokens,
+				Token{Kind: braceKind, Text: string(char), Loc: location})
 		default:
 			tokenText += string(char)
 		}
@@ -167,67 +327,225 @@ func lex(fname string, str string) []Token {
 
 type KeyKind int
 const (
-	KEY_PRINT KeyKind = iota
-	KEY_SUPER KeyKind = iota
-	KEY_SHIFT KeyKind = iota
-	KEY_NUM   KeyKind = iota
-	KEY_CHAR  KeyKind = iota
-	KEY_ENTER KeyKind = iota
+	KEY_SUPER    KeyKind = iota
+	KEY_SHIFT    KeyKind = iota
+	KEY_CTRL     KeyKind = iota
+	KEY_ALT      KeyKind = iota
+	KEY_META     KeyKind = iota
+	KEY_PRINT    KeyKind = iota
+	KEY_ENTER    KeyKind = iota
+	KEY_FUNCTION KeyKind = iota
+	KEY_KP       KeyKind = iota
+	KEY_XF86     KeyKind = iota
+	KEY_MOUSE    KeyKind = iota
+	KEY_SCROLL   KeyKind = iota
+	KEY_CHAR     KeyKind = iota
 )
 
+// Key carries both the raw token as written in the keydefs file (Raw) and a
+// normalized symbol (Name) that backends can render as-is for every kind
+// except the handful (the modifier keys, Print, Enter, Mouse*, Scroll*)
+// that each compositor spells differently - see TemplateBackend.RenderKey.
 type Key struct {
 	Kind KeyKind
+	Raw  string
+	Name string
 	Char rune
 	Num  int
 }
 
+var functionKeyPattern = regexp.MustCompile(`^F([1-9]|1[0-9]|2[0-4])$`)
+var xf86KeyPattern = regexp.MustCompile(`^XF86[A-Za-z0-9]+$`)
+var mouseKeyPattern = regexp.MustCompile(`^Mouse([1-9])$`)
+
 func stringToKey(str string, loc TokenLocation) Key {
 	switch (str) {
-	case "Print": return Key{Kind: KEY_PRINT}
-	case "Super": return Key{Kind: KEY_SUPER}
-	case "Shift": return Key{Kind: KEY_SHIFT}
-	case "Enter": return Key{Kind: KEY_ENTER}
-	default:
-		if strings.HasPrefix(str, "N_") {
-			num := strings.TrimPrefix(str, "N_")
-			if !isDigitsOnly(num) {
-				die("%s:%d:%d: Invalid `N_` key",
-					loc.File, loc.Row, loc.Col)
-			}
-
-			n, _ := strconv.Atoi(num)
-			if n > 9 {
-				die("%s:%d:%d: Keypads have only 9 keys",
-					loc.File, loc.Row, loc.Col)
-			}
+	case "Super": return Key{Kind: KEY_SUPER, Raw: str, Name: "Super"}
+	case "Shift": return Key{Kind: KEY_SHIFT, Raw: str, Name: "Shift"}
+	case "Ctrl":  return Key{Kind: KEY_CTRL, Raw: str, Name: "Ctrl"}
+	case "Alt":   return Key{Kind: KEY_ALT, Raw: str, Name: "Alt"}
+	case "Meta":  return Key{Kind: KEY_META, Raw: str, Name: "Meta"}
+	case "Print": return Key{Kind: KEY_PRINT, Raw: str, Name: "Print"}
+	case "Enter": return Key{Kind: KEY_ENTER, Raw: str, Name: "Enter"}
+	case "ScrollUp":   return Key{Kind: KEY_SCROLL, Raw: str, Name: str}
+	case "ScrollDown": return Key{Kind: KEY_SCROLL, Raw: str, Name: str}
+	}
 
-			return Key{Kind: KEY_NUM, Num: n}
-		} else {
-			if len(str) != 1 {
-				die("%s:%d:%d: Invalid character key",
-					loc.File, loc.Row, loc.Col)
-			}
-			return Key{Kind: KEY_CHAR, Char: unicode.ToUpper(rune(str[0]))}
+	if strings.HasPrefix(str, "KP_") {
+		suffix := strings.TrimPrefix(str, "KP_")
+		switch suffix {
+		case "Enter", "Add":
+			return Key{Kind: KEY_KP, Raw: str, Name: str, Num: -1}
 		}
+		if !isDigitsOnly(suffix) {
+			die("%s:%d:%d: Invalid `KP_` key: `%s`",
+				loc.File, loc.Row, loc.Col, str)
+		}
+		n, _ := strconv.Atoi(suffix)
+		if n > 9 {
+			die("%s:%d:%d: Keypads have only 10 keys (KP_0-KP_9)",
+				loc.File, loc.Row, loc.Col)
+		}
+		return Key{Kind: KEY_KP, Raw: str, Name: str, Num: n}
+	}
+
+	if match := mouseKeyPattern.FindStringSubmatch(str); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return Key{Kind: KEY_MOUSE, Raw: str, Name: str, Num: n}
+	}
+
+	if functionKeyPattern.MatchString(str) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(str, "F"))
+		return Key{Kind: KEY_FUNCTION, Raw: str, Name: str, Num: n}
+	}
+
+	if xf86KeyPattern.MatchString(str) {
+		return Key{Kind: KEY_XF86, Raw: str, Name: str}
+	}
+
+	if len(str) != 1 {
+		die("%s:%d:%d: Invalid character key: `%s`",
+			loc.File, loc.Row, loc.Col, str)
+	}
+	char := unicode.ToUpper(rune(str[0]))
+	return Key{Kind: KEY_CHAR, Raw: str, Name: string(char), Char: char}
+}
+
+// Condition gates whether a Keybinding is emitted at all, evaluated against
+// the machine/target genkeys is running on at render time. A zero-value
+// field means that predicate isn't constrained.
+type Condition struct {
+	HostPattern string
+	Target      string
+	EnvName     string
+	EnvValue    string
+	FileExists  string
+}
+
+// merge layers other on top of c: any predicate other sets wins, anything
+// it leaves zero is inherited from c. This is how nested `when` blocks
+// combine into a single effective Condition (a logical AND of every
+// enclosing block).
+func (c Condition) merge(other Condition) Condition {
+	merged := c
+	if other.HostPattern != "" {
+		merged.HostPattern = other.HostPattern
+	}
+	if other.Target != "" {
+		merged.Target = other.Target
+	}
+	if other.EnvName != "" {
+		merged.EnvName = other.EnvName
+		merged.EnvValue = other.EnvValue
+	}
+	if other.FileExists != "" {
+		merged.FileExists = other.FileExists
 	}
+	return merged
 }
 
 type Keybinding struct {
 	Keys []Key
 	Command string
+	Release bool
+	Condition Condition
 	Loc TokenLocation
 }
 
-func parseConfig(tokens []Token) []Keybinding {
+func parseConfig(tokens []Token, visited map[string]bool) []Keybinding {
 	keybindings := []Keybinding{}
+	conditions := []Condition{{}}
 
 	var i int
 	for i = 0; i < len(tokens); i++ {
 		t := tokens[i]
 
 		switch (t.Kind) {
+		case TOKEN_LBRACE:
+			die("%s:%d:%d: Unexpected `{`: blocks can only follow a `when` command",
+				t.Loc.File, t.Loc.Row, t.Loc.Col)
+		case TOKEN_RBRACE:
+			if len(conditions) <= 1 {
+				die("%s:%d:%d: Unexpected `}`: no `when` block is open",
+					t.Loc.File, t.Loc.Row, t.Loc.Col)
+			}
+			conditions = conditions[:len(conditions)-1]
 		case TOKEN_WORD:
 			switch (t.Text) {
+			case "when":
+				if (i + 1) >= len(tokens) {
+					die("%s:%d:%d: Predicate not provided for command `when`",
+						t.Loc.File, t.Loc.Row, t.Loc.Col)
+				}
+
+				predicateTok := tokens[i+1]
+				if predicateTok.Kind != TOKEN_WORD {
+					die("%s:%d:%d: `when` predicate must be a bare word",
+						predicateTok.Loc.File, predicateTok.Loc.Row, predicateTok.Loc.Col)
+				}
+
+				consumed := 1
+				var predicate Condition
+
+				nextStr := func(n int) Token {
+					if (i + n) >= len(tokens) {
+						die("%s:%d:%d: Not enough arguments for `when %s`",
+							predicateTok.Loc.File, predicateTok.Loc.Row, predicateTok.Loc.Col, predicateTok.Text)
+					}
+					tok := tokens[i+n]
+					if tok.Kind != TOKEN_STR {
+						die("%s:%d:%d: `when %s` arguments must be strings",
+							tok.Loc.File, tok.Loc.Row, tok.Loc.Col, predicateTok.Text)
+					}
+					return tok
+				}
+
+				switch (predicateTok.Text) {
+				case "host":
+					predicate.HostPattern = nextStr(2).Text
+					consumed = 2
+				case "target":
+					predicate.Target = nextStr(2).Text
+					consumed = 2
+				case "file_exists":
+					predicate.FileExists = nextStr(2).Text
+					consumed = 2
+				case "env":
+					predicate.EnvName = nextStr(2).Text
+					predicate.EnvValue = nextStr(3).Text
+					consumed = 3
+				default:
+					die("%s:%d:%d: Unknown `when` predicate: `%s`",
+						predicateTok.Loc.File, predicateTok.Loc.Row, predicateTok.Loc.Col, predicateTok.Text)
+				}
+
+				if (i + consumed + 1) >= len(tokens) || tokens[i+consumed+1].Kind != TOKEN_LBRACE {
+					die("%s:%d:%d: Expected `{` to start `when` block",
+						t.Loc.File, t.Loc.Row, t.Loc.Col)
+				}
+				consumed += 1
+
+				conditions = append(conditions, conditions[len(conditions)-1].merge(predicate))
+
+				i += consumed
+			case "include", "include_dir":
+				if (i + 1) >= len(tokens) {
+					die("%s:%d:%d: Path not provided for command `%s`",
+						t.Loc.File, t.Loc.Row, t.Loc.Col, t.Text)
+				}
+
+				pathTok := tokens[i+1]
+				if pathTok.Kind != TOKEN_STR {
+					die("%s:%d:%d: Include path must be a string",
+						pathTok.Loc.File, pathTok.Loc.Row, pathTok.Loc.Col)
+				}
+
+				targets := resolveIncludeTargets(t.Text, t.Loc.File, strings.TrimSpace(pathTok.Text), pathTok.Loc)
+				for _, target := range targets {
+					keybindings = append(keybindings, includeFile(target, visited, pathTok.Loc)...)
+				}
+
+				i += 1
 			case "bind":
 				keybinding := Keybinding{}
 
@@ -259,9 +577,18 @@ func parseConfig(tokens []Token) []Keybinding {
 
 				keys := strings.Fields(keycomb.Text)
 				for _, k := range keys {
+					if k == "release" {
+						keybinding.Release = true
+						continue
+					}
 					keybinding.Keys = append(keybinding.Keys, stringToKey(k, keycomb.Loc))
 				}
 
+				if len(keybinding.Keys) == 0 {
+					die("%s:%d:%d: Key combination must have at least one key",
+						keycomb.Loc.File, keycomb.Loc.Row, keycomb.Loc.Col)
+				}
+
 				if k := keybinding.Keys[0].Kind;
 				k == KEY_CHAR || k == KEY_ENTER {
 					die("%s:%d:%d: Key combination cannot start with `character key` or `Enter`",
@@ -269,6 +596,7 @@ func parseConfig(tokens []Token) []Keybinding {
 				}
 
 				keybinding.Command = strings.TrimSpace(execcmd.Text)
+				keybinding.Condition = conditions[len(conditions)-1]
 				keybinding.Loc = t.Loc
 
 				keybindings = append(keybindings, keybinding)
@@ -284,92 +612,343 @@ func parseConfig(tokens []Token) []Keybinding {
 		}
 	}
 
+	if len(conditions) > 1 {
+		die("%s: ERROR: Unclosed `when` block", fname(tokens))
+	}
+
 	return keybindings
 }
 
-func dumpNumKey(num int) string {
-	switch (num) {
-	case 8: return "KP_Up"
-	case 2: return "KP_Down"
-	case 4: return "KP_Left"
-	case 6: return "KP_Right"
-	case 5: return "KP_Begin"
-	case 7: return "KP_Home"
-	case 9: return "KP_Prior"
-	case 1: return "KP_End"
-	case 3: return "KP_Next"
-	default: return "?"
+// fname returns the file the last-seen token came from, for diagnostics
+// that have no specific token to anchor to (e.g. an unclosed block at EOF).
+func fname(tokens []Token) string {
+	if len(tokens) == 0 {
+		return "<empty>"
 	}
+	return tokens[len(tokens)-1].Loc.File
 }
 
-func dumpKeySway(key Key) string {
-	switch (key.Kind) {
-	case KEY_PRINT: return "Print"
-	case KEY_SUPER: return "$mod"
-	case KEY_SHIFT: return "Shift"
-	case KEY_NUM: return dumpNumKey(key.Num)
-	case KEY_CHAR: return string(unicode.ToUpper(key.Char))
-	case KEY_ENTER: return "Return"
+// TemplateKey is the representation of a Key exposed to backend templates.
+// Kind is one of "Super", "Shift", "Ctrl", "Alt", "Meta", "Print", "Enter",
+// "Function", "KP", "XF86", "Mouse", "Scroll" or "Char"; Name carries the
+// normalized symbol for the key, which is what a template should render
+// unless modName's dialect says otherwise for this Kind.
+type TemplateKey struct {
+	Kind string
+	Char string
+	Num  int
+	Name string
+}
+
+// TemplateData is the value a backend template is executed with, once per
+// Keybinding.
+type TemplateData struct {
+	Keys    []TemplateKey
+	Command string
+	Release bool
+}
+
+func keyKindName(k Key) string {
+	switch (k.Kind) {
+	case KEY_SUPER:    return "Super"
+	case KEY_SHIFT:    return "Shift"
+	case KEY_CTRL:     return "Ctrl"
+	case KEY_ALT:      return "Alt"
+	case KEY_META:     return "Meta"
+	case KEY_PRINT:    return "Print"
+	case KEY_ENTER:    return "Enter"
+	case KEY_FUNCTION: return "Function"
+	case KEY_KP:       return "KP"
+	case KEY_XF86:     return "XF86"
+	case KEY_MOUSE:    return "Mouse"
+	case KEY_SCROLL:   return "Scroll"
+	case KEY_CHAR:     return "Char"
 	default: return "?"
 	}
 }
 
-func dumpKeyHyprland(key Key) string {
-	switch (key.Kind) {
-	case KEY_PRINT: return "Print"
-	case KEY_SUPER: return "$mainMod"
-	case KEY_SHIFT: return "SHIFT"
-	case KEY_NUM: return dumpNumKey(key.Num)
-	case KEY_CHAR: return string(unicode.ToUpper(key.Char))
-	case KEY_ENTER: return "Return"
-	default: return "?"
+func toTemplateKey(k Key) TemplateKey {
+	return TemplateKey{
+		Kind: keyKindName(k),
+		Char: string(k.Char),
+		Num:  k.Num,
+		Name: k.Name,
 	}
 }
 
-func dumpKeydefsHyprland(keybindings []Keybinding, file io.Writer) {
-	w := bufio.NewWriter(file)
+// modName renders a key for a given backend dialect, the same way
+// TemplateBackend.RenderKey does for Go callers reaching a Key directly.
+// It exists because a template only ever sees the already-converted
+// TemplateKey, not the original Key RenderKey takes - every built-in
+// template calls this instead of relying on pre-rendered names, so that a
+// template can freely mix "the sway dialect" and "the hyprland dialect"
+// lookups if it wants to. Most keys - function keys, XF86 media keys,
+// numpad keys, characters - are genuine X11 keysyms every backend accepts
+// unchanged, so Name is already the right rendering; the rest (the
+// modifiers, Print, Enter, Mouse*, Scroll*) come from the named backend's
+// dialect table, first by the key's exact Name and then by Kind.
+func modName(k TemplateKey, dialect string) string {
+	backend, ok := backends[dialect]
+	if !ok {
+		return k.Name
+	}
 
-	for _, ks := range keybindings {
-		if len(ks.Keys) > 3 {
-			die("%s:%d:%d: Hyprland keybindings cannot contain more than 3 keys",
-				ks.Loc.File, ks.Loc.Row, ks.Loc.Col)
+	tb, ok := backend.(*TemplateBackend)
+	if !ok {
+		return k.Name
+	}
+
+	if name, ok := tb.dialect[k.Name]; ok {
+		return name
+	}
+	if name, ok := tb.dialect[k.Kind]; ok {
+		return name
+	}
+	return k.Name
+}
+
+// numpadName is kept as a separate helper for templates that want to
+// single out numpad keys explicitly; for the built-in backends a KP key's
+// Name is already its final rendering, so this is just k.Name.
+func numpadName(k TemplateKey) string {
+	return k.Name
+}
+
+var templateFuncs = template.FuncMap{
+	"join":       strings.Join,
+	"upper":      strings.ToUpper,
+	"quote":      func(s string) string { return strconv.Quote(s) },
+	"modName":    modName,
+	"numpadName": numpadName,
+}
+
+func parseBackendTemplate(name string, src string) *template.Template {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(src)
+	if err != nil {
+		die("ERROR: Could not parse template for `%s`: %s", name, err)
+	}
+	return tmpl
+}
+
+// Backend is everything genkeys knows how to do with a compositor's config
+// format: name it, cap how many keys a single binding may contain, and
+// render a Key or a whole Keybinding. Every backend genkeys ships today
+// happens to render through a Go text/template (see TemplateBackend), but
+// nothing about this interface requires that - a future backend could talk
+// to a compositor's IPC socket directly instead of emitting text at all.
+type Backend interface {
+	Name() string
+	MaxKeys() int
+	RenderKey(Key) (string, error)
+	RenderBinding(Keybinding, io.Writer) error
+}
+
+// TemplateBackend is the Backend every built-in and user-supplied format
+// uses: a Go text/template plus the handful of keys (the modifiers, Print,
+// Enter, Mouse*, Scroll*) that compositor spells its own way, and a cap on
+// how many keys a single binding may contain (0 means unlimited). A
+// "pluggable backend" from a user's Templates config or
+// ~/.config/genkeys/templates is just a template with no metadata of its
+// own - it inherits no cap and no dialect, which is the right default for
+// a format genkeys doesn't know anything about.
+type TemplateBackend struct {
+	name     string
+	maxKeys  int
+	dialect  map[string]string
+	template *template.Template
+}
+
+func (b *TemplateBackend) Name() string { return b.name }
+func (b *TemplateBackend) MaxKeys() int { return b.maxKeys }
+
+// RenderKey renders k the way this backend's dialect spells it, falling
+// back to k's normalized Name for every key the dialect doesn't override -
+// see modName, which does the same lookup for keys reached from inside a
+// template rather than from Go code.
+func (b *TemplateBackend) RenderKey(k Key) (string, error) {
+	tk := toTemplateKey(k)
+	if name, ok := b.dialect[tk.Name]; ok {
+		return name, nil
+	}
+	if name, ok := b.dialect[tk.Kind]; ok {
+		return name, nil
+	}
+	return tk.Name, nil
+}
+
+func (b *TemplateBackend) RenderBinding(ks Keybinding, w io.Writer) error {
+	data := TemplateData{Command: ks.Command, Release: ks.Release}
+	for _, k := range ks.Keys {
+		data.Keys = append(data.Keys, toTemplateKey(k))
+	}
+	return b.template.Execute(w, data)
+}
+
+// backends holds the backends registered by default. Built-in support for
+// Sway/Hyprland is just two embedded templates plus their key dialects and
+// key-count caps, registered here; there's nothing else special about them.
+var backends = map[string]Backend{}
+
+func registerBackend(name string, src string, maxKeys int, dialect map[string]string) {
+	backends[name] = &TemplateBackend{
+		name:     name,
+		maxKeys:  maxKeys,
+		dialect:  dialect,
+		template: parseBackendTemplate(name, src),
+	}
+}
+
+// swayMouseDialect maps Mouse1-Mouse9 and ScrollUp/ScrollDown to Sway's real
+// bindsym syntax: mouse buttons are "button<n>", and the scroll wheel is
+// "button4"/"button5" by the same legacy X11 convention i3/sway have always
+// used for wheel-up/wheel-down.
+var swayMouseDialect = map[string]string{
+	"Mouse1": "button1", "Mouse2": "button2", "Mouse3": "button3",
+	"Mouse4": "button4", "Mouse5": "button5", "Mouse6": "button6",
+	"Mouse7": "button7", "Mouse8": "button8", "Mouse9": "button9",
+	"ScrollUp": "button4", "ScrollDown": "button5",
+}
+
+// hyprlandMouseDialect maps Mouse1-Mouse9 to Hyprland's "mouse:<code>"
+// syntax (272 is BTN_LEFT, counting up the way evdev numbers mouse
+// buttons), and ScrollUp/ScrollDown to Hyprland's dedicated "mouse_up"/
+// "mouse_down" pseudo-keys for the wheel.
+var hyprlandMouseDialect = map[string]string{
+	"Mouse1": "mouse:272", "Mouse2": "mouse:273", "Mouse3": "mouse:274",
+	"Mouse4": "mouse:275", "Mouse5": "mouse:276", "Mouse6": "mouse:277",
+	"Mouse7": "mouse:278", "Mouse8": "mouse:279", "Mouse9": "mouse:280",
+	"ScrollUp": "mouse_up", "ScrollDown": "mouse_down",
+}
+
+func init() {
+	swayDialect := map[string]string{
+		"Super": "$mod",
+		"Shift": "Shift",
+		"Print": "Print",
+		"Enter": "Return",
+	}
+	for name, rendered := range swayMouseDialect {
+		swayDialect[name] = rendered
+	}
+	registerBackend("sway", swayTemplateSrc, 0, swayDialect)
+
+	hyprlandDialect := map[string]string{
+		"Super": "$mainMod",
+		"Shift": "SHIFT",
+		"Print": "Print",
+		"Enter": "Return",
+	}
+	for name, rendered := range hyprlandMouseDialect {
+		hyprlandDialect[name] = rendered
+	}
+	registerBackend("hyprland", hyprlandTemplateSrc, 3, hyprlandDialect)
+}
+
+// resolveBackend finds the template to render <name> with, in order of
+// precedence: an explicit Templates[name].Path in the config, a built-in
+// registered backend, then a user template dropped at
+// ~/.config/genkeys/templates/<name>.tmpl.
+func resolveBackend(name string, config Configuration) Backend {
+	if tmplConfig, ok := config.Templates[name]; ok && strings.TrimSpace(tmplConfig.Path) != "" {
+		file := expandUserPath(tmplConfig.Path)
+		return &TemplateBackend{name: name, template: parseBackendTemplate(name, readFileToString(file))}
+	}
+
+	if backend, ok := backends[name]; ok {
+		return backend
+	}
+
+	userTemplate := path.Join(os.Getenv("HOME"), ".config/genkeys/templates", name+".tmpl")
+	if fileExists(userTemplate) {
+		return &TemplateBackend{name: name, template: parseBackendTemplate(name, readFileToString(userTemplate))}
+	}
+
+	die("ERROR: Unknown configuration format: `%s`", name)
+	return nil
+}
+
+// outputPathFor finds where <name>'s rendered output should be written to,
+// preferring an explicit Templates[name].Output over the legacy
+// SwayPath/HyprlandPath fields.
+func outputPathFor(name string, config Configuration) string {
+	if tmplConfig, ok := config.Templates[name]; ok && strings.TrimSpace(tmplConfig.Output) != "" {
+		return tmplConfig.Output
+	}
+
+	switch (name) {
+	case "sway": return config.SwayPath
+	case "hyprland": return config.HyprlandPath
+	default: return ""
+	}
+}
+
+// conditionMatches evaluates ks's `when` predicates against the machine
+// genkeys is running on and the backend it's currently rendering for.
+func conditionMatches(c Condition, backendName string) bool {
+	if c.Target != "" && c.Target != backendName {
+		return false
+	}
+
+	if c.HostPattern != "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return false
+		}
+		matched, err := regexp.MatchString(c.HostPattern, hostname)
+		if err != nil || !matched {
+			return false
 		}
 	}
 
+	if c.EnvName != "" && os.Getenv(c.EnvName) != c.EnvValue {
+		return false
+	}
+
+	if c.FileExists != "" && !fileExists(expandUserPath(c.FileExists)) {
+		return false
+	}
+
+	return true
+}
+
+// filterKeybindings drops every Keybinding whose `when` condition doesn't
+// hold for backendName, before any backend-specific validation or
+// rendering sees them. This is what lets a Hyprland-only binding coexist
+// with the Sway output's "at most 3 keys" rule, for example.
+func filterKeybindings(backendName string, keybindings []Keybinding) []Keybinding {
+	filtered := make([]Keybinding, 0, len(keybindings))
 	for _, ks := range keybindings {
-		fmt.Fprintf(w, "bind = ")
-		// I know this is a little hacky
-		// But what's more hacky than that is hyprland's config
-		switch (len(ks.Keys)) {
-		case 1:
-			fmt.Fprintf(w, ", %s", dumpKeyHyprland(ks.Keys[0]))
-		case 2:
-			fmt.Fprintf(w, "%s, %s",
-				dumpKeyHyprland(ks.Keys[0]), dumpKeyHyprland(ks.Keys[1]))
-		case 3:
-			fmt.Fprintf(w, "%s %s, %s",
-				dumpKeyHyprland(ks.Keys[0]), dumpKeyHyprland(ks.Keys[1]), dumpKeyHyprland(ks.Keys[2]))
+		if conditionMatches(ks.Condition, backendName) {
+			filtered = append(filtered, ks)
 		}
-		fmt.Fprintf(w, ", exec, sh -c %s\n", strconv.Quote(ks.Command))
 	}
+	return filtered
+}
 
-	if err := w.Flush(); err != nil {
-		die("ERROR: Could not flush buffer: %s", err)
+// validateKeybindings applies the handful of constraints that are inherent
+// to a given compositor's config format, rather than to genkeys itself.
+func validateKeybindings(backend Backend, keybindings []Keybinding) {
+	maxKeys := backend.MaxKeys()
+	if maxKeys <= 0 {
+		return
+	}
+
+	for _, ks := range keybindings {
+		if len(ks.Keys) > maxKeys {
+			die("%s:%d:%d: %s keybindings cannot contain more than %d keys",
+				ks.Loc.File, ks.Loc.Row, ks.Loc.Col, backend.Name(), maxKeys)
+		}
 	}
 }
 
-func dumpKeydefsSway(keybindings []Keybinding, file io.Writer) {
+func renderKeydefs(backend Backend, keybindings []Keybinding, file io.Writer) {
 	w := bufio.NewWriter(file)
 
 	for _, ks := range keybindings {
-		fmt.Fprintf(w, "bindsym ")
-		for i, k := range ks.Keys {
-			fmt.Fprintf(w, dumpKeySway(k))
-			if i != (len(ks.Keys) - 1) {
-				fmt.Fprintf(w, "+")
-			}
+		if err := backend.RenderBinding(ks, w); err != nil {
+			die("%s:%d:%d: ERROR: Could not render keybinding: %s",
+				ks.Loc.File, ks.Loc.Row, ks.Loc.Col, err)
 		}
-		fmt.Fprintf(w, " exec sh -c %s\n", strconv.Quote(ks.Command))
 	}
 
 	if err := w.Flush(); err != nil {
@@ -379,46 +958,300 @@ func dumpKeydefsSway(keybindings []Keybinding, file io.Writer) {
 
 type KeyDefs []Keybinding
 
-func compileFileIntoKeydefs(file string) KeyDefs {
+// mustAbs resolves p to an absolute path, dying on any of the filesystem
+// errors filepath.Abs can surface (e.g. failing to get the working directory).
+func mustAbs(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		die("ERROR: Could not resolve path `%s`: %s", p, err)
+	}
+	return abs
+}
+
+// resolveIncludePath expands $HOME/~ in raw and, if it isn't already
+// absolute, resolves it relative to the directory of the file that's
+// including it, mirroring OpenSSH's `Include` semantics.
+func resolveIncludePath(baseFile string, raw string) string {
+	p := expandUserPath(raw)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(baseFile), p)
+	}
+	return p
+}
+
+// resolveIncludeTargets expands an `include`/`include_dir` command (cmd,
+// raw, with loc for diagnostics) into the concrete file(s) it pulls in:
+// a single file for `include`, every `*.gnks` directly inside the
+// directory for `include_dir`.
+func resolveIncludeTargets(cmd string, baseFile string, raw string, loc TokenLocation) []string {
+	resolved := resolveIncludePath(baseFile, raw)
+
+	if cmd == "include" {
+		return []string{resolved}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(resolved, "*.gnks"))
+	if err != nil {
+		die("%s:%d:%d: ERROR: Invalid pattern for `include_dir`: %s",
+			loc.File, loc.Row, loc.Col, err)
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// includeFile parses file into keybindings, guarding against include
+// cycles via visited, which tracks the chain of files currently being
+// parsed (not every file ever included, so the same file may legally be
+// included from more than one place).
+func includeFile(file string, visited map[string]bool, loc TokenLocation) []Keybinding {
+	abs := mustAbs(file)
+
+	if !fileExists(abs) {
+		die("%s:%d:%d: ERROR: Included file does not exist: `%s`",
+			loc.File, loc.Row, loc.Col, abs)
+	}
+	if visited[abs] {
+		die("%s:%d:%d: ERROR: Include cycle detected: `%s` is already being parsed",
+			loc.File, loc.Row, loc.Col, abs)
+	}
+
+	visited[abs] = true
+	keybindings := compileFileWithVisited(abs, visited)
+	delete(visited, abs)
+
+	return keybindings
+}
+
+func compileFileWithVisited(file string, visited map[string]bool) KeyDefs {
 	filecontent := readFileToString(file)
-	return parseConfig(lex(file, filecontent))
+	return parseConfig(lex(file, filecontent), visited)
+}
+
+func compileFileIntoKeydefs(file string) KeyDefs {
+	abs := mustAbs(file)
+	return compileFileWithVisited(file, map[string]bool{abs: true})
+}
+
+// TemplateConfig points genkeys at a custom backend template and the file
+// its output should be written to.
+type TemplateConfig struct {
+	Path   string
+	Output string
 }
 
 type Configuration struct {
 	WriteToFile bool
 	HyprlandPath string
 	SwayPath string
+	Templates map[string]TemplateConfig
+	ReloadCommand string
 }
 
-type ConfigFormat int
-const (
-	CONFIG_SWAY ConfigFormat = iota
-	CONFIG_HYPR ConfigFormat = iota
-	CONFIG_ALL  ConfigFormat = iota
-)
+// writeConfig renders <keydefsPath> through the <name> backend and writes
+// the result either to its configured output file or, if WriteToFile is
+// false, to stdout.
+func writeConfig(name string, config Configuration, keydefsPath string) {
+	backend := resolveBackend(name, config)
+	keydefs := filterKeybindings(name, compileFileIntoKeydefs(keydefsPath))
+
+	validateKeybindings(backend, keydefs)
 
-func writeConfigHyprland(config Configuration, keydefs string) {
 	if config.WriteToFile {
-		if strings.TrimSpace(config.HyprlandPath) == "" {
-			die("ERROR: `HyprlandPath` not defined in config")
+		outPath := outputPathFor(name, config)
+		if strings.TrimSpace(outPath) == "" {
+			die("ERROR: No output path configured for `%s`", name)
 		}
-
-		dumpKeydefsHyprland(compileFileIntoKeydefs(keydefs), getStream(config.HyprlandPath))
+		renderKeydefs(backend, keydefs, getStream(outPath))
 	} else {
-		dumpKeydefsHyprland(compileFileIntoKeydefs(keydefs), os.Stdout)
+		renderKeydefs(backend, keydefs, os.Stdout)
 	}
 }
 
-func writeConfigSway(config Configuration, keydefs string) {
-	if config.WriteToFile {
-		if strings.TrimSpace(config.SwayPath) == "" {
-			die("ERROR: `SwayPath` not defined in config")
+// writeConfigAtomic is writeConfig's counterpart for watch mode: it always
+// writes through "<outPath>.tmp" and renames it into place, so the
+// compositor never observes a half-written file, and it reports failures
+// (a typo'd keydefs file, a missing output path, ...) as an error instead
+// of exiting the process.
+func writeConfigAtomic(name string, config Configuration, keydefsPath string) error {
+	return withDieAsError(func() {
+		backend := resolveBackend(name, config)
+		keydefs := filterKeybindings(name, compileFileIntoKeydefs(keydefsPath))
+
+		validateKeybindings(backend, keydefs)
+
+		outPath := outputPathFor(name, config)
+		if strings.TrimSpace(outPath) == "" {
+			die("ERROR: No output path configured for `%s`", name)
 		}
 
-		dumpKeydefsSway(compileFileIntoKeydefs(keydefs), getStream(config.SwayPath))
-	} else {
-		dumpKeydefsSway(compileFileIntoKeydefs(keydefs), os.Stdout)
+		tmpPath := outPath + ".tmp"
+		tmpFile := getStream(tmpPath)
+		renderKeydefs(backend, keydefs, tmpFile)
+		if err := tmpFile.Close(); err != nil {
+			die("ERROR: Could not close `%s`: %s", tmpPath, err)
+		}
+		if err := os.Rename(tmpPath, outPath); err != nil {
+			die("ERROR: Could not replace `%s` with `%s`: %s", outPath, tmpPath, err)
+		}
+	})
+}
+
+// discoverKeydefsFiles walks the include graph starting at entryFile and
+// returns the absolute path of every file reached, so watch mode knows
+// what to put fsnotify watches on. Parse errors are reported through err
+// rather than die()ing, same as writeConfigAtomic.
+func discoverKeydefsFiles(entryFile string) (files []string, err error) {
+	err = withDieAsError(func() {
+		seen := map[string]bool{}
+
+		var walk func(file string, ancestors map[string]bool)
+		walk = func(file string, ancestors map[string]bool) {
+			abs := mustAbs(file)
+			if seen[abs] || !fileExists(abs) || ancestors[abs] {
+				return
+			}
+			seen[abs] = true
+			ancestors[abs] = true
+
+			tokens := lex(abs, readFileToString(abs))
+			for i := 0; i < len(tokens); i++ {
+				t := tokens[i]
+				if t.Kind != TOKEN_WORD || (t.Text != "include" && t.Text != "include_dir") {
+					continue
+				}
+				if (i+1) >= len(tokens) || tokens[i+1].Kind != TOKEN_STR {
+					continue
+				}
+
+				raw := strings.TrimSpace(tokens[i+1].Text)
+				for _, target := range resolveIncludeTargets(t.Text, abs, raw, tokens[i+1].Loc) {
+					walk(target, ancestors)
+				}
+				i += 1
+			}
+
+			delete(ancestors, abs)
+		}
+
+		walk(entryFile, map[string]bool{})
+
+		for f := range seen {
+			files = append(files, f)
+		}
+		sort.Strings(files)
+	})
+	return files, err
+}
+
+// watchTargets expands a watch-mode target into the concrete backend
+// name(s) it should regenerate, mirroring the aliasing/"all" behavior of
+// the non-watch dispatch in main(): "i3" is just Sway's alias, and "all"
+// means both Sway and Hyprland.
+func watchTargets(target string) []string {
+	switch (target) {
+	case "i3":
+		return []string{"sway"}
+	case "all":
+		return []string{"sway", "hyprland"}
+	default:
+		return []string{target}
+	}
+}
+
+// runWatch keeps genkeys running, regenerating <target>'s config whenever
+// keydefsPath or any file it (transitively) includes changes. It never
+// exits on a lex/parse error in the keydefs file - it logs it to stderr
+// and keeps watching, so fixing a typo is all it takes to recover.
+func runWatch(target string, config Configuration, keydefsPath string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		die("ERROR: Could not create filesystem watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	watched := map[string]bool{}
+	addWatch := func(file string) {
+		if watched[file] {
+			return
+		}
+		if err := watcher.Add(file); err != nil {
+			fmt.Fprintf(os.Stderr, "watch: could not watch `%s`: %s\n", file, err)
+			return
+		}
+		watched[file] = true
+	}
+
+	addWatch(keydefsPath)
+
+	reload := func() {
+		if files, err := discoverKeydefsFiles(keydefsPath); err == nil {
+			for _, f := range files {
+				addWatch(f)
+			}
+		}
+
+		for _, name := range watchTargets(target) {
+			if err := writeConfigAtomic(name, config, keydefsPath); err != nil {
+				fmt.Fprintf(os.Stderr, "watch: %s\n", err)
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "watch: regenerated `%s` config\n", name)
+		}
+
+		if cmd := strings.TrimSpace(config.ReloadCommand); cmd != "" {
+			if err := exec.Command("sh", "-c", cmd).Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "watch: reload command failed: %s\n", err)
+			}
+		}
+	}
+
+	reload()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Rename|fsnotify.Remove) != 0 {
+				// A rename-over-path (what every atomic save does, including
+				// writeConfigAtomic's own tmp+os.Rename) moves the inode our
+				// watch was bound to out from under that path, and the
+				// kernel drops the watch along with it - inotify watches a
+				// file's inode, not its path. Forget it and re-add against
+				// whatever now lives at the path, or the next edit to this
+				// file goes unnoticed forever.
+				delete(watched, event.Name)
+				if fileExists(event.Name) {
+					addWatch(event.Name)
+				}
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				reload()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "watch: filesystem watcher error: %s\n", err)
+		}
+	}
+}
+
+func loadConfiguration() Configuration {
+	config := Configuration{}
+
+	configPath := path.Join(os.Getenv("HOME"), "/.config/genkeys.json")
+	if fileExists(configPath) {
+		reader := strings.NewReader(readFileToString(configPath))
+		jsonErr := json.NewDecoder(reader).Decode(&config)
+		if jsonErr != nil {
+			die("ERROR: Could not parse config `%s`: %s", configPath, jsonErr)
+		}
 	}
+
+	return config
 }
 
 func main() {
@@ -427,13 +1260,7 @@ func main() {
 		cfgFormatStr = os.Args[1]
 	}
 
-	var cfgFormat ConfigFormat
-
-	switch (cfgFormatStr) {
-	case "sway", "i3": cfgFormat = CONFIG_SWAY
-	case "hyprland": cfgFormat = CONFIG_HYPR
-	case "all": cfgFormat = CONFIG_ALL
-	case "help":
+	if cfgFormatStr == "help" {
 		if len(os.Args) > 2 {
 			switch (os.Args[2]) {
 			case "configuring":
@@ -442,14 +1269,30 @@ func main() {
 			case "key_defs":
 				fmt.Println(KEYBINDINGS_USAGE)
 				os.Exit(0)
+			case "templates":
+				fmt.Println(TEMPLATES_USAGE)
+				os.Exit(0)
 			default:
 				die("ERROR: Unknown help page: `%s`", os.Args[2])
 			}
 		}
 		fmt.Println(USAGE)
 		os.Exit(0)
-	default:
-		die("%s\nERROR: Unknown configuration format: `%s`", USAGE, cfgFormatStr)
+	}
+
+	if cfgFormatStr == "watch" {
+		if len(os.Args) < 3 {
+			die("%s\nERROR: `watch` requires a COMP/WM target", USAGE)
+		}
+
+		target := os.Args[2]
+		fpath := path.Join(os.Getenv("HOME"), "/.config/genkeys.gnks")
+		if len(os.Args) > 3 {
+			fpath = os.Args[3]
+		}
+
+		runWatch(target, loadConfiguration(), fpath)
+		return
 	}
 
 	fpath := path.Join(os.Getenv("HOME"), "/.config/genkeys.gnks")
@@ -457,26 +1300,17 @@ func main() {
 		fpath = os.Args[2]
 	}
 
-	config := Configuration{}
+	config := loadConfiguration()
 
-	configPath := path.Join(os.Getenv("HOME"), "/.config/genkeys.json")
-	if fileExists(configPath) {
-		reader := strings.NewReader(readFileToString(configPath))
-		jsonErr := json.NewDecoder(reader).Decode(&config)
-		if jsonErr != nil {
-			die("ERROR: Could not parse config `%s`: %s", configPath, jsonErr)
-		}
-	}
-
-	switch (cfgFormat) {
-	case CONFIG_SWAY:
-		writeConfigSway(config, fpath)
-	case CONFIG_HYPR:
-		writeConfigHyprland(config, fpath)
-	case CONFIG_ALL:
-		writeConfigSway(config, fpath)
-		writeConfigHyprland(config, fpath)
+	switch (cfgFormatStr) {
+	case "sway", "i3":
+		writeConfig("sway", config, fpath)
+	case "hyprland":
+		writeConfig("hyprland", config, fpath)
+	case "all":
+		writeConfig("sway", config, fpath)
+		writeConfig("hyprland", config, fpath)
 	default:
-		die("ERROR: Saving config format `%s` is not implemented", cfgFormatStr)
+		writeConfig(cfgFormatStr, config, fpath)
 	}
 }
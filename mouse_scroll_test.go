@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+// TestMouseScrollDialect pins down the literal Sway/Hyprland syntax for
+// Mouse1-Mouse9 and ScrollUp/ScrollDown, neither of which is a real X11
+// keysym: a bare "MouseN"/"Up"/"Down" is meaningless to both compositors,
+// so modName must translate them through TemplateBackend's dialect rather
+// than passing Name through unchanged.
+func TestMouseScrollDialect(t *testing.T) {
+	cases := []struct {
+		dialect string
+		key     TemplateKey
+		want    string
+	}{
+		{"sway", TemplateKey{Kind: "Mouse", Name: "Mouse3"}, "button3"},
+		{"sway", TemplateKey{Kind: "Scroll", Name: "ScrollUp"}, "button4"},
+		{"sway", TemplateKey{Kind: "Scroll", Name: "ScrollDown"}, "button5"},
+		{"hyprland", TemplateKey{Kind: "Mouse", Name: "Mouse3"}, "mouse:274"},
+		{"hyprland", TemplateKey{Kind: "Scroll", Name: "ScrollUp"}, "mouse_up"},
+		{"hyprland", TemplateKey{Kind: "Scroll", Name: "ScrollDown"}, "mouse_down"},
+	}
+
+	for _, c := range cases {
+		if got := modName(c.key, c.dialect); got != c.want {
+			t.Errorf("modName(%+v, %q) = %q, want %q", c.key, c.dialect, got, c.want)
+		}
+	}
+}
+
+// TestRenderKeyMatchesModName checks that Backend.RenderKey - the Go-native
+// entry point a non-template backend would use - agrees with modName, the
+// path every built-in template actually renders through, for the same set
+// of dialect-translated keys.
+func TestRenderKeyMatchesModName(t *testing.T) {
+	cases := []struct {
+		dialect string
+		key     Key
+		want    string
+	}{
+		{"sway", Key{Kind: KEY_MOUSE, Name: "Mouse3", Num: 3}, "button3"},
+		{"hyprland", Key{Kind: KEY_SCROLL, Name: "ScrollUp"}, "mouse_up"},
+		{"sway", Key{Kind: KEY_SUPER, Name: "Super"}, "$mod"},
+	}
+
+	for _, c := range cases {
+		backend, ok := backends[c.dialect].(*TemplateBackend)
+		if !ok {
+			t.Fatalf("backend %q is not a *TemplateBackend", c.dialect)
+		}
+
+		got, err := backend.RenderKey(c.key)
+		if err != nil {
+			t.Fatalf("RenderKey(%+v) on %q: %s", c.key, c.dialect, err)
+		}
+		if got != c.want {
+			t.Errorf("RenderKey(%+v) on %q = %q, want %q", c.key, c.dialect, got, c.want)
+		}
+	}
+}